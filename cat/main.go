@@ -4,6 +4,8 @@ package main
 import (
 	"io"
 	"os"
+
+	"eaburns.cmds/internal/fsopen"
 )
 
 func main() {
@@ -16,8 +18,14 @@ func main() {
 		}
 	}
 	for _, path := range os.Args[1:] {
-		var file *os.File
-		if file, err = os.Open(path); err != nil {
+		backend, p, err := fsopen.Parse(path)
+		if err != nil {
+			status = 1
+			os.Stderr.WriteString(err.Error()+"\n")
+			continue
+		}
+		file, err := backend.Open(p)
+		if err != nil {
 			status = 1
 			os.Stderr.WriteString(err.Error()+"\n")
 			continue
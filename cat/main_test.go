@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"eaburns.cmds/internal/testutil"
+)
+
+func TestCatGoldenAcrossBackends(t *testing.T) {
+	const want = "hello\nworld\n"
+	backends := testutil.WriteFixture(t, map[string]string{"a.txt": want})
+	bin := testutil.Build(t, "cat")
+
+	for _, b := range backends {
+		b := b
+		t.Run(b.Name, func(t *testing.T) {
+			out, err := testutil.Run(t, bin, b.Path("a.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != want {
+				t.Errorf("got %q, want %q", out, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+// ahoCorasick matches a fixed set of literal patterns in a single
+// pass over each line, instead of evaluating one regexp per pattern.
+// Each trie node holds a goto edge per next byte, a fail link to the
+// longest proper suffix of its path that is also a node, and whether
+// that node (or any node reachable by following fail links) marks the
+// end of a pattern.
+type ahoCorasick struct {
+	goTo   []map[byte]int
+	fail   []int
+	output []bool
+}
+
+// newAhoCorasick builds the automaton for patterns. Construction
+// inserts every pattern into the trie rooted at node 0, then does a
+// BFS from the root computing each node's fail link from its parent's
+// fail link, same as the textbook Aho-Corasick construction.
+func newAhoCorasick(patterns []string) *ahoCorasick {
+	ac := &ahoCorasick{
+		goTo:   []map[byte]int{{}},
+		fail:   []int{0},
+		output: []bool{false},
+	}
+	for _, p := range patterns {
+		node := 0
+		for i := 0; i < len(p); i++ {
+			c := p[i]
+			next, ok := ac.goTo[node][c]
+			if !ok {
+				ac.goTo = append(ac.goTo, map[byte]int{})
+				ac.fail = append(ac.fail, 0)
+				ac.output = append(ac.output, false)
+				next = len(ac.goTo) - 1
+				ac.goTo[node][c] = next
+			}
+			node = next
+		}
+		ac.output[node] = true
+	}
+
+	var queue []int
+	for _, n := range ac.goTo[0] {
+		queue = append(queue, n)
+	}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		for c, v := range ac.goTo[u] {
+			queue = append(queue, v)
+			f := ac.fail[u]
+			for {
+				if n, ok := ac.goTo[f][c]; ok && n != v {
+					ac.fail[v] = n
+					break
+				}
+				if f == 0 {
+					ac.fail[v] = 0
+					break
+				}
+				f = ac.fail[f]
+			}
+			if ac.output[ac.fail[v]] {
+				ac.output[v] = true
+			}
+		}
+	}
+	return ac
+}
+
+// Match walks line byte by byte along goto edges, falling back
+// through fail links on a miss, and reports a match as soon as any
+// visited node's output set is non-empty.
+func (ac *ahoCorasick) Match(line []byte) bool {
+	node := 0
+	for _, b := range line {
+		for {
+			if n, ok := ac.goTo[node][b]; ok {
+				node = n
+				break
+			}
+			if node == 0 {
+				break
+			}
+			node = ac.fail[node]
+		}
+		if ac.output[node] {
+			return true
+		}
+	}
+	return false
+}
@@ -2,79 +2,385 @@ package main
 
 import (
 	"bufio"
-	"errors"
 	"flag"
 	"fmt"
 	"io"
+	iofs "io/fs"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
+
+	"eaburns.cmds/internal/fs"
+	"eaburns.cmds/internal/fsopen"
 )
 
 var (
-	vFlag = flag.Bool("v", false, "reverse: print lines not matching the pattern")
-	nFlag = flag.Bool("n", false, "print line numbers")
+	vFlag       = flag.Bool("v", false, "reverse: print lines not matching the pattern")
+	nFlag       = flag.Bool("n", false, "print line numbers")
+	wasmFlag    = flag.String("wasm", "", "WASM module exporting match(ptr,len int32) i32, used instead of the built-in regex")
+	rFlag       = flag.Bool("r", false, "recursively search directories")
+	hFlag       = flag.Bool("H", false, "always print the file name, even for a single file")
+	colorFlag   = flag.Bool("color", false, "highlight matches in the output")
+	fFlag       = flag.String("f", "", "read patterns from this file, one per line")
+	includeFlag = flag.String("include", "", "when recursing, only search files whose base name matches this glob")
+	excludeFlag = flag.String("exclude", "", "when recursing, skip files whose base name matches this glob")
+	maxLineFlag = flag.Int("max-line", 1<<20, "read and match lines longer than this many bytes in chunks instead of erroring")
+	aFlag       = flag.Int("A", 0, "print n lines of context after each match")
+	bFlag       = flag.Int("B", 0, "print n lines of context before each match")
+	cFlag       = flag.Int("C", 0, "print n lines of context before and after each match")
+	eFlag       patternList
 )
 
+// patternList accumulates repeated -e flags.
+type patternList []string
+
+func (p *patternList) String() string { return strings.Join(*p, ",") }
+
+func (p *patternList) Set(s string) error {
+	*p = append(*p, s)
+	return nil
+}
+
+// matcher decides whether a line matches the search criteria. The
+// built-in implementation wraps a compiled regexp; -wasm swaps in a
+// matcher backed by a user-supplied WASM module, and multiple literal
+// -e patterns are matched with an Aho-Corasick automaton instead of N
+// separate regexps.
+type matcher interface {
+	Match(line []byte) bool
+}
+
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m regexMatcher) Match(line []byte) bool {
+	return m.re.Match(line)
+}
+
+// multiMatcher matches if any of its matchers match; it is used when
+// several -e patterns are given and at least one is not a literal.
+type multiMatcher []matcher
+
+func (ms multiMatcher) Match(line []byte) bool {
+	for _, m := range ms {
+		if m.Match(line) {
+			return true
+		}
+	}
+	return false
+}
+
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] [<path> ...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [<pattern>] [<path> ...]\n", os.Args[0])
 		flag.PrintDefaults()
 	}
+	flag.Var(&eFlag, "e", "pattern to match (repeatable)")
 	flag.Parse()
 
-	if len(flag.Args()) == 0 {
-		flag.Usage()
-		os.Exit(1)
+	patterns := append([]string(nil), eFlag...)
+	if *fFlag != "" {
+		ps, err := readPatterns(*fFlag)
+		if err != nil {
+			os.Stderr.WriteString(err.Error()+"\n")
+			os.Exit(1)
+		}
+		patterns = append(patterns, ps...)
+	}
+
+	args := flag.Args()
+	if len(patterns) == 0 {
+		if len(args) == 0 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		patterns = []string{args[0]}
+		args = args[1:]
 	}
-	re, err := regexp.Compile(flag.Arg(0))
+
+	m, err := buildMatcher(patterns)
 	if err != nil {
 		os.Stderr.WriteString(err.Error()+"\n")
 		os.Exit(1)
 	}
-	if len(flag.Args()) == 1 {
-		grep(re, "", os.Stdin)
+
+	paths := args
+	if *rFlag {
+		paths = walk(args)
 	}
+
+	if len(paths) == 0 && !*rFlag {
+		status := 0
+		grep(m, "", os.Stdin, false, &status)
+		os.Exit(status)
+	}
+
+	showPath := *hFlag || len(paths) > 1
 	status := 0
-	for _, path := range flag.Args()[1:] {
-		file, err := os.Open(path)
+	for _, path := range paths {
+		backend, p, err := fsopen.Parse(path)
 		if err != nil {
 			status = 1
 			os.Stderr.WriteString(err.Error()+"\n")
 			continue
 		}
-		if err := grep(re, path, file); err != nil {
-			status = 1
-			os.Stderr.WriteString(err.Error()+"\n")
+
+		matches := []string{p}
+		if strings.ContainsAny(p, "*?[") {
+			if matches, err = fs.Glob(backend, p); err != nil {
+				status = 1
+				os.Stderr.WriteString(err.Error()+"\n")
+				continue
+			}
+		}
+		prefix := path[:len(path)-len(p)]
+		showMatchPath := showPath || len(matches) > 1
+
+		for _, mp := range matches {
+			file, err := backend.Open(mp)
+			if err != nil {
+				status = 1
+				os.Stderr.WriteString(err.Error()+"\n")
+				continue
+			}
+			grep(m, prefix+mp, file, showMatchPath, &status)
+			file.Close()
 		}
-		file.Close()
 	}
 	os.Exit(status)
 }
 
-func grep(re *regexp.Regexp, path string, r io.Reader) error {
-	in := bufio.NewReader(r)
-	lineNo := 0
-	for {
-		switch line, prefix, err := in.ReadLine(); {
-		case prefix:
-			return errors.New("Line is too long")
-		case err == io.EOF:
-			return nil
-		case err != nil:
-			return err
-		default:
-			lineNo++
-			match := re.Match(line)
-			if (match && !*vFlag) || (!match && *vFlag) {
-				if *nFlag && path != "" {
-					os.Stdout.WriteString(path+":")
+// readPatterns reads one pattern per line from path.
+func readPatterns(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if line := s.Text(); line != "" {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns, s.Err()
+}
+
+// buildMatcher picks the fastest matcher for patterns: a WASM module
+// if -wasm is given, an Aho-Corasick automaton if every pattern is a
+// plain literal (no regex metacharacters) and there is more than one,
+// or the compiled regexp(s) otherwise.
+func buildMatcher(patterns []string) (matcher, error) {
+	if *wasmFlag != "" {
+		return loadWasmMatcher(*wasmFlag)
+	}
+	if len(patterns) > 1 && allLiteral(patterns) {
+		return newAhoCorasick(patterns), nil
+	}
+	var ms multiMatcher
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, regexMatcher{re})
+	}
+	if len(ms) == 1 {
+		return ms[0], nil
+	}
+	return ms, nil
+}
+
+const regexMetaChars = `.+*?()[]{}|^$\`
+
+func allLiteral(patterns []string) bool {
+	for _, p := range patterns {
+		if strings.ContainsAny(p, regexMetaChars) {
+			return false
+		}
+	}
+	return true
+}
+
+// walk recursively collects the files under roots (the current
+// directory if roots is empty), applying -include/-exclude. A root
+// naming an archive (e.g. x.tar:sub or zip://x.zip/sub) is walked
+// through the archive's own entries rather than the local disk.
+func walk(roots []string) []string {
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	var paths []string
+	for _, root := range roots {
+		backend, p, err := fsopen.Parse(root)
+		if err != nil {
+			os.Stderr.WriteString(err.Error()+"\n")
+			continue
+		}
+		if backend == fs.Local {
+			filepath.WalkDir(root, func(p string, d iofs.DirEntry, err error) error {
+				if err != nil {
+					os.Stderr.WriteString(err.Error()+"\n")
+					return nil
+				}
+				if d.IsDir() {
+					return nil
 				}
-				if *nFlag {
-					fmt.Print(lineNo, ":")
+				if !walkFilter(filepath.Base(p)) {
+					return nil
 				}
-				os.Stdout.WriteString(string(line)+"\n")
+				paths = append(paths, p)
+				return nil
+			})
+			continue
+		}
+
+		prefix := root[:len(root)-len(p)]
+		ents, err := fs.Walk(backend, p)
+		if err != nil {
+			os.Stderr.WriteString(err.Error()+"\n")
+			continue
+		}
+		for _, e := range ents {
+			if !walkFilter(filepath.Base(e)) {
+				continue
 			}
+			paths = append(paths, prefix+e)
+		}
+	}
+	return paths
+}
+
+// walkFilter reports whether a file with the given base name should be
+// kept, per -include/-exclude.
+func walkFilter(base string) bool {
+	if *includeFlag != "" {
+		if ok, _ := filepath.Match(*includeFlag, base); !ok {
+			return false
+		}
+	}
+	if *excludeFlag != "" {
+		if ok, _ := filepath.Match(*excludeFlag, base); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// grep streams r line by line, printing lines that match (or, with
+// -v, don't match) m, along with any requested context lines.
+func grep(m matcher, path string, r io.Reader, showPath bool, status *int) {
+	before, after := contextSizes()
+	in := bufio.NewReaderSize(r, 64<<10)
+
+	var beforeBuf [][]byte
+	afterRemaining := 0
+	lineNo := 0
+	lastPrinted := 0
+
+	for {
+		line, err := readChunk(in, *maxLineFlag)
+		if len(line) == 0 && err == io.EOF {
+			return
+		}
+		lineNo++
+		match := m.Match(line)
+		show := (match && !*vFlag) || (!match && *vFlag)
+
+		switch {
+		case show:
+			start := lineNo - len(beforeBuf)
+			if (before > 0 || after > 0) && lastPrinted > 0 && start > lastPrinted+1 {
+				os.Stdout.WriteString("--\n")
+			}
+			for i, b := range beforeBuf {
+				printLine(path, start+i, b, showPath, m, false)
+			}
+			beforeBuf = nil
+			printLine(path, lineNo, line, showPath, m, match && !*vFlag)
+			lastPrinted = lineNo
+			afterRemaining = after
+		case afterRemaining > 0:
+			printLine(path, lineNo, line, showPath, m, false)
+			afterRemaining--
+			lastPrinted = lineNo
+		case before > 0:
+			beforeBuf = append(beforeBuf, append([]byte(nil), line...))
+			if len(beforeBuf) > before {
+				beforeBuf = beforeBuf[1:]
+			}
+		}
+
+		if err != nil {
+			if err != io.EOF {
+				*status = 1
+				os.Stderr.WriteString(err.Error()+"\n")
+			}
+			return
+		}
+	}
+}
+
+// contextSizes derives the before/after context line counts from
+// -A/-B/-C; -C sets both unless -A or -B overrides it.
+func contextSizes() (before, after int) {
+	before, after = *bFlag, *aFlag
+	if *cFlag > 0 {
+		if before == 0 {
+			before = *cFlag
+		}
+		if after == 0 {
+			after = *cFlag
+		}
+	}
+	return before, after
+}
+
+func printLine(path string, lineNo int, line []byte, showPath bool, m matcher, isMatch bool) {
+	var prefix string
+	if showPath {
+		prefix += path + ":"
+	}
+	if *nFlag {
+		prefix += strconv.Itoa(lineNo) + ":"
+	}
+	text := string(line)
+	if *colorFlag && isMatch {
+		text = colorize(m, text)
+	}
+	os.Stdout.WriteString(prefix+text+"\n")
+}
+
+func colorize(m matcher, s string) string {
+	if rm, ok := m.(regexMatcher); ok {
+		if loc := rm.re.FindStringIndex(s); loc != nil {
+			return s[:loc[0]] + "\x1b[31m" + s[loc[0]:loc[1]] + "\x1b[0m" + s[loc[1]:]
+		}
+	}
+	return "\x1b[31m" + s + "\x1b[0m"
+}
+
+// readChunk reads the next logical line from in. Lines longer than
+// max are matched and returned in chunks instead of failing with a
+// "line too long" error; a chunk holds whole pieces from in's buffer
+// and ends once it reaches max bytes, so it can exceed max by less
+// than the buffer's size. err is io.EOF only once in is
+// exhausted with no remaining data.
+func readChunk(in *bufio.Reader, max int) ([]byte, error) {
+	var buf []byte
+	for {
+		part, prefix, err := in.ReadLine()
+		buf = append(buf, part...)
+		if err != nil {
+			return buf, err
+		}
+		if !prefix || len(buf) >= max {
+			return buf, nil
 		}
 	}
-	panic("Unreachable")
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"eaburns.cmds/internal/testutil"
+)
+
+func TestGrepGoldenAcrossBackends(t *testing.T) {
+	backends := testutil.WriteFixture(t, map[string]string{
+		"a.txt": "foo\nbar\nfoobar\n",
+	})
+	bin := testutil.Build(t, "grep")
+
+	const want = "foo\nfoobar\n"
+	for _, b := range backends {
+		b := b
+		t.Run(b.Name, func(t *testing.T) {
+			out, err := testutil.Run(t, bin, "foo", b.Path("a.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != want {
+				t.Errorf("got %q, want %q", out, want)
+			}
+		})
+	}
+}
+
+// TestGrepGlobAcrossBackends exercises the request's own example
+// (grep pattern zip://x.zip/*.go) against every backend, not just zip.
+func TestGrepGlobAcrossBackends(t *testing.T) {
+	backends := testutil.WriteFixture(t, map[string]string{
+		"a.go":  "package a\n// match\n",
+		"b.go":  "package b\n",
+		"c.txt": "match\n",
+	})
+	bin := testutil.Build(t, "grep")
+
+	for _, b := range backends {
+		b := b
+		t.Run(b.Name, func(t *testing.T) {
+			out, err := testutil.Run(t, bin, "match", b.Path("*.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out, "match") {
+				t.Errorf("got %q, want it to contain the match from a.go", out)
+			}
+			if strings.Contains(out, "c.txt") {
+				t.Errorf("glob *.go should not have matched c.txt: %q", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+//go:build wasm_filter
+
+package main
+
+import (
+	"os"
+
+	"github.com/go-interpreter/wagon/exec"
+	"github.com/go-interpreter/wagon/wasm"
+)
+
+// wasmMatcher runs a user-supplied WASM module to decide whether a
+// line matches. The module must export a function
+// match(ptr, len int32) -> i32, returning non-zero for a match. Line
+// bytes are copied into the module's linear memory before each call.
+type wasmMatcher struct {
+	vm        *exec.VM
+	matchFunc int64
+}
+
+// loadWasmMatcher compiles and instantiates the module at path. One
+// VM is created per call, so that callers can hand one VM to each
+// worker goroutine.
+func loadWasmMatcher(path string) (matcher, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := wasm.ReadModule(f, nil)
+	if err != nil {
+		return nil, err
+	}
+	vm, err := exec.NewVM(m)
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := m.Export.Entries["match"]
+	if !ok {
+		return nil, errNoExport("match")
+	}
+	return &wasmMatcher{vm: vm, matchFunc: int64(fn.Index)}, nil
+}
+
+func (w *wasmMatcher) Match(line []byte) bool {
+	mem := w.vm.Memory()
+	copy(mem, line)
+	ret, err := w.vm.ExecCode(w.matchFunc, uint64(0), uint64(len(line)))
+	if err != nil {
+		return false
+	}
+	return ret.(uint32) != 0
+}
+
+type errNoExport string
+
+func (e errNoExport) Error() string {
+	return "wasm module has no exported function " + string(e)
+}
@@ -0,0 +1,58 @@
+//go:build wasm_filter
+
+package main
+
+import (
+	"os"
+	"regexp"
+	"testing"
+)
+
+// alwaysMatchWasm is a hand-assembled, minimal WASM module (no
+// compiler involved) exporting match(ptr, len int32) -> i32, which
+// always returns 1. It exists only so the benchmarks below can drive
+// a real wagon VM call without depending on an external WASM toolchain
+// to produce a fixture.
+var alwaysMatchWasm = []byte{
+	0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00, // magic, version
+	0x01, 0x07, 0x01, 0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7f, // type: (i32,i32) -> i32
+	0x03, 0x02, 0x01, 0x00, // function: type 0
+	0x05, 0x03, 0x01, 0x00, 0x01, // memory: 1 page
+	0x07, 0x09, 0x01, 0x05, 'm', 'a', 't', 'c', 'h', 0x00, 0x00, // export "match"
+	0x0A, 0x06, 0x01, 0x04, 0x00, 0x41, 0x01, 0x0B, // code: i32.const 1
+}
+
+// BenchmarkMatchRegex and BenchmarkMatchWasm compare the throughput of
+// the built-in regexp matcher against a WASM-backed matcher on the
+// same input, to quantify the per-line overhead of crossing into the
+// wagon VM versus a native regexp.Match call.
+func BenchmarkMatchRegex(b *testing.B) {
+	m := regexMatcher{re: regexp.MustCompile(`needle`)}
+	line := []byte("the quick brown fox jumps over the lazy dog needle and more text")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Match(line)
+	}
+}
+
+func BenchmarkMatchWasm(b *testing.B) {
+	f, err := os.CreateTemp("", "match-*.wasm")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(alwaysMatchWasm); err != nil {
+		b.Fatal(err)
+	}
+	f.Close()
+
+	m, err := loadWasmMatcher(f.Name())
+	if err != nil {
+		b.Fatal(err)
+	}
+	line := []byte("the quick brown fox jumps over the lazy dog needle and more text")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Match(line)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !wasm_filter
+
+package main
+
+import "errors"
+
+// loadWasmMatcher is stubbed out unless built with the wasm_filter
+// tag, so that systems without github.com/go-interpreter/wagon can
+// still build grep.
+func loadWasmMatcher(path string) (matcher, error) {
+	return nil, errors.New("grep: -wasm requires a build with -tags wasm_filter")
+}
@@ -0,0 +1,197 @@
+package fs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Archive is a read-only Interface over the contents of a tar or zip
+// file, indexed by the archive member's own path.
+type Archive struct {
+	entries map[string]*archiveEntry
+}
+
+type archiveEntry struct {
+	data  []byte
+	mode  os.FileMode
+	mtime time.Time
+}
+
+// OpenTar reads path as a tar file and returns a read-only Interface
+// over its members.
+func OpenTar(path string) (*Archive, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	a := &Archive{entries: make(map[string]*archiveEntry)}
+	r := tar.NewReader(f)
+	for {
+		hdr, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		a.entries[hdr.Name] = &archiveEntry{data: data, mode: hdr.FileInfo().Mode(), mtime: hdr.ModTime}
+	}
+	return a, nil
+}
+
+// OpenZip reads path as a zip file and returns a read-only Interface
+// over its members.
+func OpenZip(path string) (*Archive, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	a := &Archive{entries: make(map[string]*archiveEntry)}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		a.entries[f.Name] = &archiveEntry{data: data, mode: f.Mode(), mtime: f.Modified}
+	}
+	return a, nil
+}
+
+func (a *Archive) Open(name string) (File, error) {
+	e, ok := a.entries[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return &archiveFile{name: name, entry: e, buf: bytes.NewReader(e.data)}, nil
+}
+
+func (a *Archive) Create(name string) (File, error) {
+	return nil, &os.PathError{Op: "create", Path: name, Err: os.ErrPermission}
+}
+
+// Stat stats name. Most tar and zip files carry no explicit header
+// for their member directories, so a name with no entry of its own is
+// still reported as an (empty) directory as long as it is a prefix of
+// some entry's path; this includes the archive root ("" or ".").
+func (a *Archive) Stat(name string) (os.FileInfo, error) {
+	name = cleanArchiveName(name)
+	if e, ok := a.entries[name]; ok {
+		return archiveFileInfo{name: path.Base(name), entry: e}, nil
+	}
+	if name == "" || a.isDir(name) {
+		return archiveDirInfo{name: path.Base(name)}, nil
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+// isDir reports whether name is an implicit directory: one with no
+// entry of its own, but that is an ancestor of at least one entry.
+func (a *Archive) isDir(name string) bool {
+	prefix := name + "/"
+	for p := range a.entries {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanArchiveName normalizes name the way archive member paths are
+// keyed: no "." for the root, no trailing slash.
+func cleanArchiveName(name string) string {
+	name = path.Clean(name)
+	if name == "." {
+		return ""
+	}
+	return strings.TrimSuffix(name, "/")
+}
+
+func (a *Archive) Chtimes(name string, atime, mtime time.Time) error {
+	return &os.PathError{Op: "chtimes", Path: name, Err: os.ErrPermission}
+}
+
+func (a *Archive) ReadDir(name string) ([]os.FileInfo, error) {
+	var infos []os.FileInfo
+	prefix := strings.TrimPrefix(cleanArchiveName(name)+"/", "/")
+	seen := make(map[string]bool)
+	for p, e := range a.entries {
+		rel := strings.TrimSuffix(strings.TrimPrefix(p, prefix), "/")
+		child, _, nested := strings.Cut(rel, "/")
+		if !strings.HasPrefix(p, prefix) || rel == "" || child == "." || seen[child] {
+			continue
+		}
+		seen[child] = true
+		if nested {
+			infos = append(infos, archiveDirInfo{name: child})
+		} else {
+			infos = append(infos, archiveFileInfo{name: child, entry: e})
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos, nil
+}
+
+type archiveFile struct {
+	name  string
+	entry *archiveEntry
+	buf   *bytes.Reader
+}
+
+func (f *archiveFile) Read(p []byte) (int, error) { return f.buf.Read(p) }
+
+func (f *archiveFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: os.ErrPermission}
+}
+
+func (f *archiveFile) Close() error { return nil }
+
+func (f *archiveFile) Stat() (os.FileInfo, error) {
+	return archiveFileInfo{name: path.Base(f.name), entry: f.entry}, nil
+}
+
+type archiveFileInfo struct {
+	name  string
+	entry *archiveEntry
+}
+
+func (i archiveFileInfo) Name() string       { return i.name }
+func (i archiveFileInfo) Size() int64        { return int64(len(i.entry.data)) }
+func (i archiveFileInfo) Mode() os.FileMode  { return i.entry.mode }
+func (i archiveFileInfo) ModTime() time.Time { return i.entry.mtime }
+func (i archiveFileInfo) IsDir() bool        { return i.entry.mode.IsDir() }
+func (i archiveFileInfo) Sys() interface{}   { return nil }
+
+// archiveDirInfo is the os.FileInfo for an implicit directory: one
+// with no tar/zip header of its own, inferred from being a prefix of
+// some entry's path.
+type archiveDirInfo struct {
+	name string
+}
+
+func (i archiveDirInfo) Name() string       { return i.name }
+func (i archiveDirInfo) Size() int64        { return 0 }
+func (i archiveDirInfo) Mode() os.FileMode  { return os.ModeDir | 0555 }
+func (i archiveDirInfo) ModTime() time.Time { return time.Time{} }
+func (i archiveDirInfo) IsDir() bool        { return true }
+func (i archiveDirInfo) Sys() interface{}   { return nil }
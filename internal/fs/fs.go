@@ -0,0 +1,34 @@
+// Package fs defines a small filesystem interface so that the
+// commands in this repository can open, stat, and create files
+// without calling os.* directly. This lets a command run unchanged
+// against the local disk, an in-memory filesystem, or a read-only
+// view onto the contents of an archive.
+package fs
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// File is the subset of *os.File that commands need once a file is
+// open. *os.File satisfies this interface, so the local backend can
+// return os.Open's result directly.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+	Stat() (os.FileInfo, error)
+}
+
+// Interface is implemented by filesystem backends. Commands that
+// want to work across backends should take an Interface instead of
+// calling os.Open, os.Stat, os.Create, os.Chtimes, or
+// (*os.File).Readdir directly.
+type Interface interface {
+	Open(name string) (File, error)
+	Create(name string) (File, error)
+	Stat(name string) (os.FileInfo, error)
+	Chtimes(name string, atime, mtime time.Time) error
+	ReadDir(name string) ([]os.FileInfo, error)
+}
@@ -0,0 +1,85 @@
+package fs
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Glob expands pattern against backend's directory entries, supporting
+// the usual shell glob metacharacters (*, ?, [...]) in any path
+// component. A pattern with no metacharacters is returned unexpanded,
+// without checking that the path exists.
+func Glob(backend Interface, pattern string) ([]string, error) {
+	if !hasMeta(pattern) {
+		return []string{pattern}, nil
+	}
+
+	dir, file := path.Split(pattern)
+	dir = path.Clean(dir)
+
+	var dirs []string
+	if hasMeta(dir) {
+		var err error
+		dirs, err = Glob(backend, dir)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		dirs = []string{dir}
+	}
+
+	var matches []string
+	for _, d := range dirs {
+		ents, err := backend.ReadDir(d)
+		if err != nil {
+			continue
+		}
+		for _, ent := range ents {
+			ok, err := path.Match(file, ent.Name())
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			if d == "." {
+				matches = append(matches, ent.Name())
+			} else {
+				matches = append(matches, d+"/"+ent.Name())
+			}
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// Walk recursively collects the paths of every non-directory entry
+// under root.
+func Walk(backend Interface, root string) ([]string, error) {
+	ents, err := backend.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, ent := range ents {
+		p := ent.Name()
+		if root != "" && root != "." {
+			p = path.Join(root, ent.Name())
+		}
+		if !ent.IsDir() {
+			paths = append(paths, p)
+			continue
+		}
+		sub, err := Walk(backend, p)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, sub...)
+	}
+	return paths, nil
+}
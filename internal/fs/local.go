@@ -0,0 +1,33 @@
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Local is the Interface backed by the local disk. It is the default
+// backend used when a path carries no archive scheme.
+var Local Interface = localFS{}
+
+type localFS struct{}
+
+func (localFS) Open(name string) (File, error) {
+	return os.Open(name)
+}
+
+func (localFS) Create(name string) (File, error) {
+	return os.Create(name)
+}
+
+func (localFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (localFS) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+func (localFS) ReadDir(name string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(name)
+}
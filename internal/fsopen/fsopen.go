@@ -0,0 +1,49 @@
+// Package fsopen resolves a command-line path argument to the
+// fs.Interface backend that should serve it plus the plain path to
+// use against that backend. It understands two archive path forms:
+//
+//	foo.tar:path/inside     a tar file, member path after the colon
+//	zip://x.zip/path/inside a zip file, member path after the archive
+//
+// A path matching neither form is served by the local disk.
+package fsopen
+
+import (
+	"fmt"
+	"strings"
+
+	"eaburns.cmds/internal/fs"
+)
+
+// Parse returns the backend that should serve path and the path to
+// pass to it. Archive backends are opened eagerly, since both the
+// tar and zip readers need to scan the whole archive up front.
+func Parse(p string) (fs.Interface, string, error) {
+	if rest := strings.TrimPrefix(p, "zip://"); rest != p {
+		// Split on the ".zip" extension itself, rather than the first
+		// "/", since archivePath is often an absolute path and so
+		// contains slashes of its own.
+		i := strings.Index(rest, ".zip")
+		if i < 0 {
+			return nil, "", fmt.Errorf("fsopen: %q is not a zip:// path", p)
+		}
+		archivePath := rest[:i+len(".zip")]
+		inner := strings.TrimPrefix(rest[i+len(".zip"):], "/")
+		a, err := fs.OpenZip(archivePath)
+		if err != nil {
+			return nil, "", err
+		}
+		return a, inner, nil
+	}
+
+	if i := strings.Index(p, ".tar:"); i >= 0 {
+		archivePath, inner := p[:i+len(".tar")], p[i+len(".tar:"):]
+		a, err := fs.OpenTar(archivePath)
+		if err != nil {
+			return nil, "", err
+		}
+		return a, inner, nil
+	}
+
+	return fs.Local, p, nil
+}
@@ -0,0 +1,132 @@
+// Package testutil provides shared helpers for golden-style tests that
+// exercise a command's built binary against every fs.Interface
+// backend: the local disk, a tar archive, and a zip archive, all
+// holding the same fixture content.
+package testutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// Backend names one of the fs.Interface backends a golden test runs
+// against.
+type Backend struct {
+	// Name labels the backend in subtest names.
+	Name string
+	// Path returns the command-line path that selects this backend
+	// for the fixture file called name.
+	Path func(name string) string
+}
+
+// WriteFixture writes files (name -> contents) to a temp directory,
+// builds a tar and a zip archive with the same contents in a separate
+// temp directory (so a local-backend directory listing of the fixture
+// sees only the fixture files, not the archives), and returns a
+// Backend for each of the three.
+func WriteFixture(t *testing.T, files map[string]string) []Backend {
+	t.Helper()
+	dir := t.TempDir()
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archiveDir := t.TempDir()
+	tarPath := filepath.Join(archiveDir, "fixture.tar")
+	writeTar(t, tarPath, files)
+
+	zipPath := filepath.Join(archiveDir, "fixture.zip")
+	writeZip(t, zipPath, files)
+
+	return []Backend{
+		{Name: "local", Path: func(name string) string { return filepath.Join(dir, name) }},
+		{Name: "tar", Path: func(name string) string { return tarPath + ":" + name }},
+		{Name: "zip", Path: func(name string) string { return "zip://" + zipPath + "/" + name }},
+	}
+}
+
+func writeTar(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := tar.NewWriter(f)
+	defer w.Close()
+	for name, contents := range files {
+		hdr := &tar.Header{Name: name, Size: int64(len(contents)), Mode: 0644}
+		if err := w.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	defer w.Close()
+	for name, contents := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Build compiles the command package at pkgDir (a module-root-relative
+// directory, e.g. "cat") to a temp binary and returns its path.
+func Build(t *testing.T, pkgDir string) string {
+	t.Helper()
+	bin := filepath.Join(t.TempDir(), filepath.Base(pkgDir))
+	cmd := exec.Command("go", "build", "-o", bin, "./"+pkgDir)
+	cmd.Dir = moduleRoot(t)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build ./%s: %v\n%s", pkgDir, err, out)
+	}
+	return bin
+}
+
+// moduleRoot walks up from the current working directory to find the
+// directory containing go.mod.
+func moduleRoot(t *testing.T) string {
+	t.Helper()
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			t.Fatal("go.mod not found above " + dir)
+		}
+		dir = parent
+	}
+}
+
+// Run runs bin with args and returns its stdout.
+func Run(t *testing.T, bin string, args ...string) (string, error) {
+	t.Helper()
+	out, err := exec.Command(bin, args...).Output()
+	return string(out), err
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// lsColors holds the parsed LS_COLORS environment variable: keys are
+// either a two-letter type code ("di", "ln", "ex", ...) or a
+// "*.ext" glob, values are the raw SGR parameter list to wrap names
+// in (e.g. "01;34").
+var lsColors = parseLSColors(os.Getenv("LS_COLORS"))
+
+func parseLSColors(s string) map[string]string {
+	m := make(map[string]string)
+	for _, entry := range strings.Split(s, ":") {
+		k, v, ok := strings.Cut(entry, "=")
+		if !ok || k == "" || v == "" {
+			continue
+		}
+		m[k] = v
+	}
+	return m
+}
+
+// colorEnabled reports whether output should be colorized: "always"
+// unconditionally, "auto" only when stdout is a terminal, "never" (the
+// default) not at all.
+func colorEnabled() bool {
+	switch *colorFlag {
+	case "always":
+		return true
+	case "auto":
+		return isTerminal(os.Stdout)
+	default:
+		return false
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize returns the ANSI escape that should precede i's name, or
+// "" if colorization is disabled or LS_COLORS has no matching entry.
+func colorize(i listItem) string {
+	if !colorEnabled() {
+		return ""
+	}
+	var code string
+	switch {
+	case i.info.Mode()&os.ModeSymlink != 0:
+		code = lsColors["ln"]
+	case i.info.IsDir():
+		code = lsColors["di"]
+	case i.info.Mode()&0111 != 0:
+		code = lsColors["ex"]
+	default:
+		code = lsColors["*"+filepath.Ext(i.path)]
+	}
+	if code == "" {
+		return ""
+	}
+	return "\x1b[" + code + "m"
+}
+
+// colorReset returns the ANSI reset sequence if colorize would have
+// emitted an escape for i, so that callers can unconditionally pair
+// colorize(i) + name + colorReset(i).
+func colorReset(i listItem) string {
+	if colorize(i) == "" {
+		return ""
+	}
+	return "\x1b[0m"
+}
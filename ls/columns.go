@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// printColumns prints items one per line (-1, or when stdout isn't a
+// terminal) or in as many terminal-width-aware columns as fit,
+// flowing down each column before starting the next, the way ls does.
+func printColumns(items listItems) error {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = colorize(item) + item.pathName() + colorReset(item)
+	}
+	widths := make([]int, len(items))
+	for i, item := range items {
+		widths[i] = len(item.pathName())
+	}
+
+	if *oneLine || !isTerminal(os.Stdout) || len(names) == 0 {
+		for _, n := range names {
+			if _, err := os.Stdout.WriteString(n+"\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	width := termWidth()
+	maxw := 0
+	for _, w := range widths {
+		if w > maxw {
+			maxw = w
+		}
+	}
+	colWidth := maxw + 2
+	cols := width / colWidth
+	if cols < 1 {
+		cols = 1
+	}
+	rows := (len(names) + cols - 1) / cols
+
+	for r := 0; r < rows; r++ {
+		line := ""
+		for c := 0; c < cols; c++ {
+			idx := c*rows + r
+			if idx >= len(names) {
+				break
+			}
+			pad := colWidth - widths[idx]
+			if c == cols-1 || idx+rows >= len(names) {
+				pad = 0
+			}
+			line += names[idx] + spaces(pad)
+		}
+		if _, err := os.Stdout.WriteString(line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func spaces(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = ' '
+	}
+	return string(b)
+}
+
+// termWidth returns the terminal width to lay columns out for, read
+// from the controlling terminal via term.GetSize, falling back to 80
+// when stdout isn't a terminal or the ioctl fails.
+func termWidth() int {
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		return w
+	}
+	return 80
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"syscall"
+)
+
+// jsonEntry is one NDJSON record emitted by -json.
+type jsonEntry struct {
+	Path   string `json:"path"`
+	Mode   string `json:"mode"`
+	UID    int    `json:"uid"`
+	GID    int    `json:"gid"`
+	Size   int64  `json:"size"`
+	MTime  string `json:"mtime"`
+	Target string `json:"target,omitempty"`
+}
+
+// printJSON writes one JSON object per item to stdout, newline
+// delimited, so ls -json output can feed structured pipelines.
+func printJSON(items listItems) error {
+	enc := json.NewEncoder(os.Stdout)
+	for _, i := range items {
+		e := jsonEntry{
+			Path:  i.path,
+			Mode:  i.info.Mode().String(),
+			UID:   -1,
+			GID:   -1,
+			Size:  i.info.Size(),
+			MTime: i.info.ModTime().Format("2006-01-02T15:04:05Z07:00"),
+		}
+		if sys, ok := i.info.Sys().(*syscall.Stat_t); ok {
+			e.UID = int(sys.Uid)
+			e.GID = int(sys.Gid)
+		}
+		if lstat, err := os.Lstat(i.path); err == nil && lstat.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Readlink(i.path); err == nil {
+				e.Target = target
+			}
+		}
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,13 +4,15 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io"
 	"os"
 	"os/user"
 	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
 	"syscall"
+
+	"eaburns.cmds/internal/fsopen"
 )
 
 var (
@@ -18,6 +20,15 @@ var (
 	longFormat    = flag.Bool("l", false, "Print each item with a longer format")
 	baseName      = flag.Bool("p", false, "Only print the base name of each entry")
 	classify      = flag.Bool("F", false, "Print / after directories")
+	humanSizes    = flag.Bool("h", false, "Print sizes in human-readable (IEC) units with -l")
+	allFlag       = flag.Bool("a", false, "Do not hide entries starting with .")
+	timeSort      = flag.Bool("t", false, "Sort by modification time, newest first")
+	sizeSort      = flag.Bool("S", false, "Sort by size, largest first")
+	reverseSort   = flag.Bool("r", false, "Reverse the sort order")
+	recursive     = flag.Bool("R", false, "List subdirectories recursively")
+	oneLine       = flag.Bool("1", false, "List one entry per line")
+	jsonFlag      = flag.Bool("json", false, "Print one JSON object per entry instead of a table")
+	colorFlag     = flag.String("color", "never", "Colorize output using LS_COLORS: auto, always, or never")
 )
 
 type errors []error
@@ -42,61 +53,100 @@ func main() {
 	}
 
 	status := 0
-	var items listItems
-	for _, path := range paths {
-		is, err := getItems(path)
-		if err != nil {
+	header := len(paths) > 1 || *recursive
+	for i, path := range paths {
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := listPath(path, header); err != nil {
 			status = 1
 			os.Stderr.WriteString(err.Error()+"\n")
 		}
-		items = append(items, is...)
 	}
 
-	sort.Sort(items)
-	for _, item := range items {
-		var err error
-		if *longFormat {
-			err = item.printLong()
-		} else {
-			err = item.print()
+	os.Exit(status)
+}
+
+// listPath lists path, printing a "path:" header first when header is
+// set, and recursing into subdirectories when -R is given.
+func listPath(path string, header bool) error {
+	items, err := getItems(path)
+	items.sortItems()
+
+	if header {
+		fmt.Println(path + ":")
+	}
+
+	var printErr error
+	switch {
+	case *jsonFlag:
+		printErr = printJSON(items)
+	case *longFormat:
+		var errs errors
+		for _, item := range items {
+			if err := item.printLong(); err != nil {
+				errs = append(errs, err)
+			}
 		}
-		if err != nil {
-			status = 1
-			os.Stderr.WriteString(err.Error()+"\n")
+		if errs != nil {
+			printErr = errs
 		}
+	default:
+		printErr = printColumns(items)
+	}
+	if printErr != nil {
+		os.Stderr.WriteString(printErr.Error()+"\n")
 	}
 
-	os.Exit(status)
+	if *recursive && !*listDirectory {
+		for _, item := range items {
+			if !item.info.Mode().IsDir() {
+				continue
+			}
+			fmt.Println()
+			if err := listPath(item.path, true); err != nil {
+				os.Stderr.WriteString(err.Error()+"\n")
+			}
+		}
+	}
+
+	return err
 }
 
 // getItems returns all of the items to be listed.
-func getItems(path string) ([]listItem, error) {
-	info, err := os.Stat(path)
+func getItems(path string) (listItems, error) {
+	backend, p, err := fsopen.Parse(path)
 	if err != nil {
 		return nil, err
 	}
-	if !info.Mode().IsDir() || *listDirectory {
-		return []listItem{{path, info}}, nil
-	}
-	dir, err := os.Open(path)
+	info, err := backend.Stat(p)
 	if err != nil {
 		return nil, err
 	}
-	defer dir.Close()
+	if !info.Mode().IsDir() || *listDirectory {
+		return listItems{{path, info}}, nil
+	}
 
-	ents, err := dir.Readdirnames(-1)
-	if err != nil && err != io.EOF {
+	ents, err := backend.ReadDir(p)
+	if err != nil {
 		return nil, err
 	}
 
-	var items []listItem
+	var items listItems
 	var errs errors
 	for _, ent := range ents {
-		p := filepath.Join(path, ent)
-		if info, err := os.Stat(p); err != nil {
+		if !*allFlag && strings.HasPrefix(ent.Name(), ".") {
+			continue
+		}
+		entPath := filepath.Join(path, ent.Name())
+		entP := ent.Name()
+		if p != "" {
+			entP = strings.TrimSuffix(p, "/") + "/" + ent.Name()
+		}
+		if info, err := backend.Stat(entP); err != nil {
 			errs = append(errs, err)
 		} else {
-			items = append(items, listItem{p, info})
+			items = append(items, listItem{entPath, info})
 		}
 	}
 	if errs != nil {
@@ -111,20 +161,27 @@ type listItem struct {
 	info os.FileInfo
 }
 
-// listItems is a slice of listItems, implementing
-// sort.Interface.
+// listItems is a slice of listItems.
 type listItems []listItem
 
-func (l listItems) Len() int {
-	return len(l)
-}
-
-func (l listItems) Swap(i, j int) {
-	l[i], l[j] = l[j], l[i]
-}
-
-func (l listItems) Less(i, j int) bool {
-	return l[i].path < l[j].path
+// sortItems orders items by name, modification time (-t), or size
+// (-S), reversing the order when -r is given.
+func (l listItems) sortItems() {
+	less := func(i, j int) bool {
+		switch {
+		case *timeSort:
+			return l[i].info.ModTime().After(l[j].info.ModTime())
+		case *sizeSort:
+			return l[i].info.Size() > l[j].info.Size()
+		default:
+			return l[i].path < l[j].path
+		}
+	}
+	if *reverseSort {
+		sort.Slice(l, func(i, j int) bool { return less(j, i) })
+		return
+	}
+	sort.Slice(l, less)
 }
 
 // pathName returns the path name of this item.
@@ -139,33 +196,44 @@ func (i listItem) pathName() string {
 	return p
 }
 
-// print prints the item.
-func (i listItem) print() error {
-	_, err := os.Stdout.WriteString(i.pathName()+"\n")
-	return err
-}
-
-// printLong prints the item in the long format.
+// printLong prints the item in the long format: mode, link count,
+// owner, group, size, modification time, and name. Symlinks are
+// resolved and printed as "name -> target".
 func (i listItem) printLong() error {
-	uid, gid := -1, -1
+	uid, gid, nlink := -1, -1, 1
 	if sys, ok := i.info.Sys().(*syscall.Stat_t); ok {
 		uid = int(sys.Uid)
 		gid = int(sys.Gid)
+		nlink = int(sys.Nlink)
 	}
 
-	userStr := strconv.Itoa(uid)
 	var errs errors
+	userStr := strconv.Itoa(uid)
 	if u, err := user.LookupId(userStr); err != nil {
 		errs = append(errs, err)
 	} else {
 		userStr = u.Username
 	}
 
-	size := i.info.Size()
-	time := i.info.ModTime().Format("Jan 2 15:04")
+	groupStr := strconv.Itoa(gid)
+	if g, err := user.LookupGroupId(groupStr); err != nil {
+		errs = append(errs, err)
+	} else {
+		groupStr = g.Name
+	}
+
+	size := sizeString(i.info.Size())
+	modTime := i.info.ModTime().Format("Jan 2 15:04")
 	mode := i.info.Mode().String()
 	name := i.pathName()
-	if _, err := fmt.Println(mode, userStr, gid, size, time, name); err != nil {
+	if lstat, err := os.Lstat(i.path); err == nil && lstat.Mode()&os.ModeSymlink != 0 {
+		if target, err := os.Readlink(i.path); err == nil {
+			name += " -> " + target
+		}
+	}
+
+	line := fmt.Sprintf("%s %d %s %s %s %s %s%s%s", mode, nlink, userStr, groupStr, size, modTime, colorize(i), name, colorReset(i))
+	if _, err := fmt.Println(line); err != nil {
 		errs = append(errs, err)
 	}
 
@@ -174,3 +242,21 @@ func (i listItem) printLong() error {
 	}
 	return errs
 }
+
+// sizeString formats n as a plain byte count, or with -h as an
+// IEC-suffixed human-readable size (e.g. 1.5K, 3.2M).
+func sizeString(n int64) string {
+	if !*humanSizes {
+		return strconv.FormatInt(n, 10)
+	}
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"eaburns.cmds/internal/testutil"
+)
+
+func TestLsGoldenAcrossBackends(t *testing.T) {
+	backends := testutil.WriteFixture(t, map[string]string{
+		"a.txt": "a",
+		"b.txt": "b",
+	})
+	bin := testutil.Build(t, "ls")
+
+	for _, b := range backends {
+		b := b
+		t.Run(b.Name, func(t *testing.T) {
+			out, err := testutil.Run(t, bin, "-1", "-p", b.Path(""))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != "a.txt\nb.txt\n" {
+				t.Errorf("got %q, want %q", out, "a.txt\nb.txt\n")
+			}
+		})
+	}
+}
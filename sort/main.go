@@ -2,28 +2,67 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"container/heap"
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"os"
+	"runtime"
 	"sort"
-	"flag"
-	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"eaburns.cmds/internal/fsopen"
 )
 
-const (
-	chunkSize = 500000
-	mergeSize = 10
+// defaultMemBudget is the in-memory chunk budget used when -S is not
+// given.
+const defaultMemBudget = 64 << 20
+
+// readBufSize is the size of the bufio.Reader kept open for each
+// chunk file during a merge pass; it bounds how many chunk files can
+// be merged at once within the memory budget.
+const readBufSize = 64 << 10
+
+var (
+	nflag       = flag.Bool("n", false, "Sort lines using a numeric prefix")
+	wasmKeyFlag = flag.String("wasm-key", "", "WASM module exporting key(ptr,len,outptr,outcap int32) i32, used to derive the sort key instead of the line itself")
+	memFlag     = flag.String("S", "", "Memory budget per chunk, e.g. 64M or 1G (default 64M)")
+	fieldFlag   = flag.Int("k", 0, "Compare the Nth whitespace-separated field (1-based) instead of the whole line")
+	rflag       = flag.Bool("r", false, "Reverse the sort order")
+	uflag       = flag.Bool("u", false, "Output only the first of each run of equal lines")
+	sflag       = flag.Bool("s", false, "Stabilize the sort, breaking ties using input order")
+	cflag       = flag.Bool("c", false, "Check whether the input is already sorted instead of sorting it")
 )
 
-var nflag = flag.Bool("n", false, "Sort lines using a numeric prefix")
+// keyFunc derives the sort key for a line. It is nil unless -wasm-key
+// is given, in which case makeLine uses it instead of comparing the
+// raw line, a field, or a numeric prefix.
+type keyFunc func(line string) string
+
+var wasmKey keyFunc
 
 func main() {
 	flag.Parse()
 
+	if *wasmKeyFlag != "" {
+		var err error
+		if wasmKey, err = loadWasmKey(*wasmKeyFlag); err != nil {
+			os.Stderr.WriteString(err.Error()+"\n")
+			os.Exit(1)
+		}
+	}
+
 	errs := make(chan error)
-	go mergeSort(flag.Args(), errs)
+	if *cflag {
+		go checkSorted(flag.Args(), errs)
+	} else {
+		go mergeSort(flag.Args(), errs)
+	}
 
 	status := 0
 	for err := range errs {
@@ -33,34 +72,49 @@ func main() {
 	os.Exit(status)
 }
 
+// mergeSort reads paths (or stdin), sorts the input in memory-bounded
+// chunks sorted concurrently by a worker pool, and merges the
+// resulting runs with an optimal-k multi-way merge chosen from the
+// memory budget.
 func mergeSort(paths []string, errs chan<- error) {
-	lines := readAllLines(paths, errs)
-	var tmps []string
-	for c := range chunks(lines, chunkSize) {
-		if len(c) < chunkSize && len(tmps) == 0 {
-			out := bufio.NewWriter(os.Stdout)
-			defer out.Flush()
-			for _, l := range c {
-				out.WriteString(l.str+"\n")
-			}
-			goto out
-		}
-		if tmp, err := writeChunk(c); err != nil {
-			errs <- err
-			goto out
-		} else {
-			tmps = append(tmps, tmp)
+	budget, err := parseSize(*memFlag)
+	if err != nil {
+		errs <- err
+		close(errs)
+		return
+	}
+
+	var created []string
+	var mu sync.Mutex
+	track := func(name string) {
+		mu.Lock()
+		created = append(created, name)
+		mu.Unlock()
+	}
+	defer func() {
+		for _, t := range created {
+			os.Remove(t)
 		}
+	}()
+
+	lines := readAllLines(paths, errs)
+	tmps, err := sortChunksConcurrently(lines, budget, track)
+	if err != nil {
+		errs <- err
+		close(errs)
+		return
 	}
 
-	for len(tmps) > mergeSize {
+	k := optimalK(budget)
+	for len(tmps) > k {
 		f, err := ioutil.TempFile(os.TempDir(), "sort")
 		if err != nil {
 			errs <- err
 			break
 		}
-		err = merge(f, tmps[:mergeSize])
-		tmps = append(tmps[mergeSize:], f.Name())
+		track(f.Name())
+		err = mergePass(f, tmps[:k], false, false)
+		tmps = append(tmps[k:], f.Name())
 		f.Close()
 		if err != nil {
 			errs <- err
@@ -68,16 +122,43 @@ func mergeSort(paths []string, errs chan<- error) {
 		}
 	}
 	if len(tmps) > 0 {
-		if err := merge(os.Stdout, tmps); err != nil {
+		if err := mergePass(os.Stdout, tmps, *uflag, true); err != nil {
 			errs <- err
 		}
 	}
+	close(errs)
+}
 
-out:
-	for _, t := range tmps {
-		os.Remove(t)
+// optimalK returns the number of chunk files that can be merged in a
+// single pass without exceeding budget, given that each open chunk
+// file holds a readBufSize read buffer.
+func optimalK(budget int64) int {
+	k := int(budget / readBufSize)
+	if k < 2 {
+		k = 2
 	}
-	close(errs)
+	return k
+}
+
+// parseSize parses a byte count with an optional K, M, or G suffix.
+func parseSize(s string) (int64, error) {
+	if s == "" {
+		return defaultMemBudget, nil
+	}
+	mult := int64(1)
+	switch s[len(s)-1] {
+	case 'k', 'K':
+		mult, s = 1<<10, s[:len(s)-1]
+	case 'm', 'M':
+		mult, s = 1<<20, s[:len(s)-1]
+	case 'g', 'G':
+		mult, s = 1<<30, s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid -S value: %v", err)
+	}
+	return n * mult, nil
 }
 
 type chunk []line
@@ -94,20 +175,25 @@ func (c chunk) Less(i, j int) bool {
 	return c[i].less(c[j])
 }
 
-func chunks(lines <-chan string, sz int) <-chan chunk {
+// chunkByBytes groups lines into chunks whose total line length
+// reaches budget, rather than a hard-coded line count, so that -S
+// bounds the memory a chunk actually uses.
+func chunkByBytes(lines <-chan string, budget int64, seq *int64) <-chan chunk {
 	ch := make(chan chunk)
 	go func(ch chan<- chunk) {
-		c := make(chunk, 0, sz)
+		var c chunk
+		var size int64
 		for l := range lines {
-			c = append(c, makeLine(l))
-			if len(c) == sz {
-				sort.Sort(c)
+			c = append(c, makeLine(l, *seq))
+			*seq++
+			size += int64(len(l))
+			if size >= budget {
 				ch <- c
-				c = make(chunk, 0, sz)
+				c = nil
+				size = 0
 			}
 		}
 		if len(c) > 0 {
-			sort.Sort(c)
 			ch <- c
 		}
 		close(ch)
@@ -115,6 +201,56 @@ func chunks(lines <-chan string, sz int) <-chan chunk {
 	return ch
 }
 
+// sortChunksConcurrently splits lines into budget-sized chunks, sorts
+// each chunk in a worker pool sized to GOMAXPROCS, and spills every
+// sorted chunk to a temp file. track is called with the name of every
+// temp file created, so the caller can clean up on error.
+func sortChunksConcurrently(lines <-chan string, budget int64, track func(string)) ([]string, error) {
+	var seq int64
+	raw := chunkByBytes(lines, budget, &seq)
+
+	type result struct {
+		tmp string
+		err error
+	}
+	results := make(chan result)
+	var wg sync.WaitGroup
+	workers := runtime.GOMAXPROCS(0)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range raw {
+				if *sflag {
+					sort.Stable(c)
+				} else {
+					sort.Sort(c)
+				}
+				tmp, err := writeChunk(c)
+				results <- result{tmp, err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var tmps []string
+	var firstErr error
+	for r := range results {
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
+		}
+		track(r.tmp)
+		tmps = append(tmps, r.tmp)
+	}
+	return tmps, firstErr
+}
+
 func writeChunk(c chunk) (string, error) {
 	f, err := ioutil.TempFile(os.TempDir(), "sort")
 	if err != nil {
@@ -125,8 +261,12 @@ func writeChunk(c chunk) (string, error) {
 	out := bufio.NewWriter(f)
 	defer out.Flush()
 
+	// Each line's global sequence number is written ahead of its text
+	// so that -s stability survives the round trip through this file:
+	// without it, the k-way merge could only see position within this
+	// chunk's sorted output, not true input order.
 	for _, l := range c {
-		if _, err := out.WriteString(l.str+"\n"); err != nil {
+		if _, err := fmt.Fprintf(out, "%d\t%s\n", l.seq, l.str); err != nil {
 			os.Remove(f.Name())
 			return "", err
 		}
@@ -145,29 +285,37 @@ func newChunkFile(p string) (*chunkFile, error) {
 	if err != nil {
 		return nil, err
 	}
-	in := bufio.NewReader(f)
-	// prefix cannot be true since we wrote these
-	// lines and we didn't write one that is too long.
-	bytes, _, err := in.ReadLine()
-	if err != nil {
-		os.Remove(f.Name())
-		f.Close()
+	cf := &chunkFile{file: f, in: bufio.NewReaderSize(f, readBufSize)}
+	if err := cf.nextLine(); err != nil {
 		return nil, err
 	}
-	return &chunkFile{file: f, in: in, cur: makeLine(string(bytes))}, nil
+	return cf, nil
 }
 
+// nextLine reads the next record written by writeChunk, restoring its
+// original global sequence number rather than renumbering it.
 func (c *chunkFile) nextLine() error {
-	bytes, _, err := c.in.ReadLine()
+	b, _, err := c.in.ReadLine()
 	if err != nil {
 		os.Remove(c.file.Name())
 		c.file.Close()
 		return err
 	}
-	c.cur = makeLine(string(bytes))
+	c.cur = parseChunkLine(b)
 	return nil
 }
 
+// parseChunkLine splits a "seq\ttext" record back into a line with its
+// original seq, falling back to seq 0 if the tab is missing.
+func parseChunkLine(b []byte) line {
+	i := bytes.IndexByte(b, '\t')
+	if i < 0 {
+		return makeLine(string(b), 0)
+	}
+	seq, _ := strconv.ParseInt(string(b[:i]), 10, 64)
+	return makeLine(string(b[i+1:]), seq)
+}
+
 type chunkHeap []*chunkFile
 
 func (h chunkHeap) Len() int {
@@ -192,7 +340,17 @@ func (h *chunkHeap) Pop() interface{} {
 	return x
 }
 
-func merge(w io.Writer, paths []string) error {
+// mergePass does a single k-way merge of paths into w. When unique is
+// set, only the first of each run of equal lines (per the active
+// comparator) is written; this is used for the final pass only, since
+// a sorted merge keeps equal lines adjacent at every intermediate
+// pass too. When final is false, w is itself a chunk file that will
+// be read back by a later mergePass, so each line is written in the
+// same "seq\ttext" record format as writeChunk uses; this carries the
+// true global sequence number through every merge round, not just the
+// first. When final is true, w is the user-visible output, so only
+// the line's text is written.
+func mergePass(w io.Writer, paths []string, unique, final bool) error {
 	var q chunkHeap
 	for _, p := range paths {
 		if c, err := newChunkFile(p); err != nil {
@@ -204,11 +362,23 @@ func merge(w io.Writer, paths []string) error {
 
 	out := bufio.NewWriter(w)
 	defer out.Flush()
+	var prev line
+	havePrev := false
 	for len(q) > 0 {
 		c := heap.Pop(&q).(*chunkFile)
-		if _, err := out.WriteString(c.cur.str+"\n"); err != nil {
-			return err
+		cur := c.cur
+		if !unique || !havePrev || !cur.equalKey(prev) {
+			var err error
+			if final {
+				_, err = out.WriteString(cur.str + "\n")
+			} else {
+				_, err = fmt.Fprintf(out, "%d\t%s\n", cur.seq, cur.str)
+			}
+			if err != nil {
+				return err
+			}
 		}
+		prev, havePrev = cur, true
 		if err := c.nextLine(); err == nil {
 			heap.Push(&q, c)
 		} else if err != io.EOF {
@@ -218,26 +388,87 @@ func merge(w io.Writer, paths []string) error {
 	return nil
 }
 
+// checkSorted reports whether the input is already sorted according
+// to the active comparator, without writing any output. It exits
+// non-zero and reports the first out-of-order line if not.
+func checkSorted(paths []string, errs chan<- error) {
+	lines := readAllLines(paths, errs)
+	var prev line
+	var seq int64
+	havePrev := false
+	for s := range lines {
+		cur := makeLine(s, seq)
+		seq++
+		if havePrev && cur.less(prev) {
+			errs <- fmt.Errorf("disorder: %s", cur.str)
+			close(errs)
+			return
+		}
+		prev, havePrev = cur, true
+	}
+	close(errs)
+}
+
 type line struct {
 	num int
 	str string
+	key string
+	seq int64
 }
 
-func makeLine(s string) line {
-	var num int
-	if *nflag {
-		if n, err := fmt.Sscanf(s, "%d", &num); n != 1 || err != nil {
-			num = int(math.MinInt32)
+func makeLine(s string, seq int64) line {
+	l := line{str: s, seq: seq}
+	switch {
+	case wasmKey != nil:
+		l.key = wasmKey(s)
+	case *fieldFlag > 0:
+		l.key = fieldKey(s, *fieldFlag)
+	case *nflag:
+		if n, err := fmt.Sscanf(s, "%d", &l.num); n != 1 || err != nil {
+			l.num = int(math.MinInt32)
 		}
 	}
-	return line{str: s, num: num}
+	return l
+}
+
+// fieldKey returns the nth (1-based) whitespace-separated field of s,
+// or "" if s has fewer than n fields.
+func fieldKey(s string, n int) string {
+	fields := strings.Fields(s)
+	if n-1 >= len(fields) {
+		return ""
+	}
+	return fields[n-1]
 }
 
 func (a line) less(b line) bool {
+	lt, gt := a.compare(b)
+	if !lt && !gt && *sflag {
+		return a.seq < b.seq
+	}
+	if *rflag {
+		return gt
+	}
+	return lt
+}
+
+// equalKey reports whether a and b compare equal under the active
+// comparator, ignoring input order.
+func (a line) equalKey(b line) bool {
+	lt, gt := a.compare(b)
+	return !lt && !gt
+}
+
+// compare returns (a < b, a > b) under the active comparator:
+// wasm key, field key, numeric prefix, or the whole line.
+func (a line) compare(b line) (lt, gt bool) {
+	if wasmKey != nil || *fieldFlag > 0 {
+		return a.key < b.key, a.key > b.key
+	}
 	if *nflag {
-		return a.num < b.num
+		return a.num < b.num, a.num > b.num
 	}
-	return a.str < b.str
+	return a.str < b.str, a.str > b.str
 }
 
 func readAllLines(paths []string, errs chan<- error) <-chan string {
@@ -257,7 +488,12 @@ func readAllLines(paths []string, errs chan<- error) <-chan string {
 func readLines(path string, lines chan<- string, errs chan<- error) {
 	var r io.Reader = os.Stdin
 	if path != "-" {
-		f, err := os.Open(path)
+		backend, p, err := fsopen.Parse(path)
+		if err != nil {
+			errs <- err
+			return
+		}
+		f, err := backend.Open(p)
 		if err != nil {
 			errs <- err
 			return
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"eaburns.cmds/internal/testutil"
+)
+
+func TestSortGoldenAcrossBackends(t *testing.T) {
+	backends := testutil.WriteFixture(t, map[string]string{
+		"a.txt": "banana\napple\ncherry\n",
+	})
+	bin := testutil.Build(t, "sort")
+
+	const want = "apple\nbanana\ncherry\n"
+	for _, b := range backends {
+		b := b
+		t.Run(b.Name, func(t *testing.T) {
+			out, err := testutil.Run(t, bin, b.Path("a.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != want {
+				t.Errorf("got %q, want %q", out, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+//go:build wasm_filter
+
+package main
+
+import (
+	"os"
+
+	"github.com/go-interpreter/wagon/exec"
+	"github.com/go-interpreter/wagon/wasm"
+)
+
+// loadWasmKey compiles and instantiates the module at path, and
+// returns a keyFunc backed by its exported
+// key(ptr, len, outptr, outcap int32) -> i32 function. The module
+// writes the derived sort key into its own linear memory at outptr
+// and returns the key's length (or a negative value on error).
+func loadWasmKey(path string) (keyFunc, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := wasm.ReadModule(f, nil)
+	if err != nil {
+		return nil, err
+	}
+	vm, err := exec.NewVM(m)
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := m.Export.Entries["key"]
+	if !ok {
+		return nil, errNoExport("key")
+	}
+	const outCap = 4096
+	const outPtr = 0
+	idx := int64(fn.Index)
+	return func(s string) string {
+		mem := vm.Memory()
+		inPtr := uint64(outCap)
+		copy(mem[inPtr:], s)
+		n, err := vm.ExecCode(idx, inPtr, uint64(len(s)), uint64(outPtr), uint64(outCap))
+		if err != nil {
+			return s
+		}
+		length := int32(n.(uint32))
+		if length < 0 {
+			return s
+		}
+		return string(mem[outPtr : outPtr+int(length)])
+	}, nil
+}
+
+type errNoExport string
+
+func (e errNoExport) Error() string {
+	return "wasm module has no exported function " + string(e)
+}
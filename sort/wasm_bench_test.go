@@ -0,0 +1,60 @@
+//go:build wasm_filter
+
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// echoLenWasm is a hand-assembled, minimal WASM module (no compiler
+// involved) exporting key(ptr, len, outptr, outcap int32) -> i32,
+// which always returns its len argument unchanged. It exists only so
+// the benchmarks below can drive a real wagon VM call without
+// depending on an external WASM toolchain to produce a fixture.
+var echoLenWasm = []byte{
+	0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00, // magic, version
+	0x01, 0x09, 0x01, 0x60, 0x04, 0x7f, 0x7f, 0x7f, 0x7f, 0x01, 0x7f, // type: (i32 x4) -> i32
+	0x03, 0x02, 0x01, 0x00, // function: type 0
+	0x05, 0x03, 0x01, 0x00, 0x01, // memory: 1 page
+	0x07, 0x07, 0x01, 0x03, 'k', 'e', 'y', 0x00, 0x00, // export "key"
+	0x0A, 0x06, 0x01, 0x04, 0x00, 0x20, 0x01, 0x0B, // code: local.get 1
+}
+
+// BenchmarkKeyWholeLine and BenchmarkKeyWasm compare the throughput of
+// the built-in whole-line key comparator against a WASM-backed key
+// function on the same input, to quantify the per-line overhead of
+// crossing into the wagon VM versus comparing native Go strings.
+func BenchmarkKeyWholeLine(b *testing.B) {
+	s := "the quick brown fox jumps over the lazy dog and some more text"
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = makeLine(s, int64(i))
+	}
+}
+
+func BenchmarkKeyWasm(b *testing.B) {
+	f, err := os.CreateTemp("", "key-*.wasm")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(echoLenWasm); err != nil {
+		b.Fatal(err)
+	}
+	f.Close()
+
+	key, err := loadWasmKey(f.Name())
+	if err != nil {
+		b.Fatal(err)
+	}
+	prevWasmKey := wasmKey
+	wasmKey = key
+	defer func() { wasmKey = prevWasmKey }()
+
+	s := "the quick brown fox jumps over the lazy dog and some more text"
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = makeLine(s, int64(i))
+	}
+}
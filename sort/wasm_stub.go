@@ -0,0 +1,12 @@
+//go:build !wasm_filter
+
+package main
+
+import "errors"
+
+// loadWasmKey is stubbed out unless built with the wasm_filter tag,
+// so that systems without github.com/go-interpreter/wagon can still
+// build sort.
+func loadWasmKey(path string) (keyFunc, error) {
+	return nil, errors.New("sort: -wasm-key requires a build with -tags wasm_filter")
+}
@@ -1,4 +1,4 @@
-// touch sets the modification time of a file or files.
+// touch sets the access and/or modification time of a file or files.
 package main
 
 import (
@@ -6,16 +6,45 @@ import (
 	"fmt"
 	"os"
 	"time"
+
+	"eaburns.cmds/internal/fs"
+	"eaburns.cmds/internal/fsopen"
+	"eaburns.cmds/touch/stat"
 )
 
 var (
-	create = flag.Bool("c", true, "Create files that do not exist")
-	mtime  = flag.String("t", "", "The modification time to set (YYYY-MM-DD:HH:MM:SS)")
+	create  = flag.Bool("c", true, "Create files that do not exist")
+	mtime   = flag.String("t", "", "The time to set (YYYY-MM-DD:HH:MM:SS)")
+	dFlag   = flag.String("d", "", "The time to set, as a flexible date string (RFC3339 or a common date format)")
+	rFlag   = flag.String("r", "", "Copy the access and modification times from this file instead of using the current time")
+	aFlag   = flag.Bool("a", false, "Change only the access time")
+	mFlag   = flag.Bool("m", false, "Change only the modification time")
+	hFlag   = flag.Bool("h", false, "Act on a symlink itself, rather than the file it points to")
 )
 
+// dateLayouts are tried in order when parsing -d.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"01/02/2006 15:04:05",
+	"01/02/2006",
+}
+
+func parseFlexibleDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("touch: cannot parse date %q", s)
+}
+
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [<path> ...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [<path> ...]\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 	flag.Parse()
@@ -25,24 +54,36 @@ func main() {
 		os.Exit(1)
 	}
 
-	t := time.Now()
-	if *mtime != "" {
-		var err error
-		t, err = time.Parse("2006-01-02:15:04:05", *mtime)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
-		}
+	targetAtime, targetMtime, err := targetTimes()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// -a/-m restrict which of the two times is actually changed; the
+	// other is preserved from the file's current times.
+	updAtime, updMtime := true, true
+	if *aFlag && !*mFlag {
+		updMtime = false
+	}
+	if *mFlag && !*aFlag {
+		updAtime = false
 	}
 
 	status := 0
 	for _, path := range flag.Args() {
-		_, err := os.Stat(path)
+		backend, p, err := fsopen.Parse(path)
+		if err != nil {
+			status = 1
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		_, err = backend.Stat(p)
 		if os.IsNotExist(err) {
 			if !*create {
 				continue
 			}
-			if f, err := os.Create(path); err != nil {
+			if f, err := backend.Create(p); err != nil {
 				status = 1
 				fmt.Fprintln(os.Stderr, err)
 				continue
@@ -54,10 +95,51 @@ func main() {
 			fmt.Fprintln(os.Stderr, err)
 			continue
 		}
-		if err = os.Chtimes(path, t, t); err != nil {
+
+		at, mt := targetAtime, targetMtime
+		if !updAtime || !updMtime {
+			curAtime, curMtime, err := stat.Times(path)
+			if err != nil {
+				status = 1
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+			if !updAtime {
+				at = curAtime
+			}
+			if !updMtime {
+				mt = curMtime
+			}
+		}
+
+		if backend == fs.Local {
+			err = setTimes(path, at, mt, !*hFlag)
+		} else {
+			err = backend.Chtimes(p, at, mt)
+		}
+		if err != nil {
 			status = 1
 			fmt.Fprintln(os.Stderr, err)
 		}
 	}
 	os.Exit(status)
 }
+
+// targetTimes returns the access and modification times that touch
+// should apply, from -r, -d, -t, or the current time, in that order
+// of precedence.
+func targetTimes() (at, mt time.Time, err error) {
+	switch {
+	case *rFlag != "":
+		return stat.Times(*rFlag)
+	case *dFlag != "":
+		t, err := parseFlexibleDate(*dFlag)
+		return t, t, err
+	case *mtime != "":
+		t, err := time.Parse("2006-01-02:15:04:05", *mtime)
+		return t, t, err
+	default:
+		now := time.Now()
+		return now, now, nil
+	}
+}
@@ -0,0 +1,24 @@
+// Package stat reads a file's access and modification times together,
+// so that callers updating only one of the two (touch's -a/-m) can
+// preserve the other.
+package stat
+
+import (
+	"os"
+	"time"
+)
+
+// Times returns the access and modification times of the file at
+// path, following symlinks. If the underlying stat doesn't expose
+// both times, it falls back to ModTime for both.
+func Times(path string) (atime, mtime time.Time, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	atime, mtime, ok := sysTimes(info)
+	if !ok {
+		return info.ModTime(), info.ModTime(), nil
+	}
+	return atime, mtime, nil
+}
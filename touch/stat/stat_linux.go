@@ -0,0 +1,20 @@
+//go:build linux
+
+package stat
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// sysTimes extracts the access and modification times from info's
+// underlying *syscall.Stat_t, reporting false if info doesn't carry
+// one.
+func sysTimes(info os.FileInfo) (atime, mtime time.Time, ok bool) {
+	sys, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return time.Unix(sys.Atim.Sec, sys.Atim.Nsec), time.Unix(sys.Mtim.Sec, sys.Mtim.Nsec), true
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package stat
+
+import (
+	"os"
+	"time"
+)
+
+// sysTimes has no platform-specific access time to extract here, so
+// it always reports false and lets Times fall back to ModTime.
+func sysTimes(info os.FileInfo) (atime, mtime time.Time, ok bool) {
+	return time.Time{}, time.Time{}, false
+}
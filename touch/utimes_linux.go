@@ -0,0 +1,45 @@
+//go:build linux
+
+package main
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Linux-specific utimensat(2) constants; not exported by the syscall
+// package, so declared here with their stable ABI values.
+const atSymlinkNoFollow = 0x100
+
+// atFDCWD is declared as a var, not a const, because Go forbids
+// converting a negative constant directly to an unsigned type.
+var atFDCWD int32 = -100
+
+// setTimes sets path's access and modification times with nanosecond
+// precision via utimensat(2), optionally acting on the symlink itself
+// (AT_SYMLINK_NOFOLLOW) instead of its target.
+func setTimes(path string, atime, mtime time.Time, followSymlink bool) error {
+	times := [2]syscall.Timespec{
+		syscall.NsecToTimespec(atime.UnixNano()),
+		syscall.NsecToTimespec(mtime.UnixNano()),
+	}
+	pathPtr, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	var flags uintptr
+	if !followSymlink {
+		flags = atSymlinkNoFollow
+	}
+	_, _, errno := syscall.Syscall6(
+		syscall.SYS_UTIMENSAT,
+		uintptr(uint32(atFDCWD)),
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&times[0])),
+		flags, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// setTimes falls back to os.Chtimes on platforms without a
+// nanosecond, symlink-aware utimes syscall wired up here; it always
+// follows symlinks, since os.Chtimes does.
+func setTimes(path string, atime, mtime time.Time, followSymlink bool) error {
+	return os.Chtimes(path, atime, mtime)
+}
@@ -6,32 +6,139 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"flag"
+	"fmt"
 	"io"
 	"os"
+
+	"eaburns.cmds/internal/fsopen"
+)
+
+var (
+	cFlag = flag.Bool("c", false, "Prefix each output line with its repetition count")
+	dFlag = flag.Bool("d", false, "Only print lines that are repeated")
+	uFlag = flag.Bool("u", false, "Only print lines that are not repeated")
+	iFlag = flag.Bool("i", false, "Ignore case when comparing lines")
+	fFlag = flag.Int("f", 0, "Skip this many leading whitespace-separated fields before comparing")
+	sFlag = flag.Int("s", 0, "Skip this many leading characters before comparing")
+	wFlag = flag.Int("w", 0, "Compare at most this many characters; 0 means the whole (post-skip) line")
 )
 
 var stdin = bufio.NewReader(os.Stdin)
 var stdout = bufio.NewWriter(os.Stdout)
 
+// comparator reports whether two lines are considered equal, given
+// the active -i/-f/-s/-w flags. It is built once from the flags so
+// the hot loop doesn't re-check them per line.
+type comparator func(a, b []byte) bool
+
+func buildComparator() comparator {
+	f, s, w, fold := *fFlag, *sFlag, *wFlag, *iFlag
+	return func(a, b []byte) bool {
+		return bytes.Equal(compareKey(a, f, s, w, fold), compareKey(b, f, s, w, fold))
+	}
+}
+
+// compareKey returns the portion of line that the -f/-s/-w/-i values
+// f, s, w and fold select for comparison.
+func compareKey(line []byte, f, s, w int, fold bool) []byte {
+	if f > 0 {
+		line = skipFields(line, f)
+	}
+	if s > 0 {
+		line = skipChars(line, s)
+	}
+	if w > 0 && len(line) > w {
+		line = line[:w]
+	}
+	if fold {
+		line = bytes.ToLower(line)
+	}
+	return line
+}
+
+// skipFields returns line with its first n whitespace-separated
+// fields (and the whitespace that follows them) removed.
+func skipFields(line []byte, n int) []byte {
+	for i := 0; i < n; i++ {
+		line = bytes.TrimLeft(line, " \t")
+		j := bytes.IndexAny(line, " \t")
+		if j < 0 {
+			return nil
+		}
+		line = line[j:]
+	}
+	return line
+}
+
+// skipChars returns line with its first n characters removed.
+func skipChars(line []byte, n int) []byte {
+	if n >= len(line) {
+		return nil
+	}
+	return line[n:]
+}
+
 func main() {
+	flag.Parse()
 	defer stdout.Flush()
-	var prevLine []byte
+
+	if args := flag.Args(); len(args) > 0 {
+		backend, p, err := fsopen.Parse(args[0])
+		if err != nil {
+			die(err)
+		}
+		f, err := backend.Open(p)
+		if err != nil {
+			die(err)
+		}
+		defer f.Close()
+		stdin = bufio.NewReader(f)
+	}
+
+	equal := buildComparator()
+
+	var group []byte
+	haveGroup := false
+	count := 0
+
+	flush := func() {
+		if !haveGroup {
+			return
+		}
+		if *dFlag && count < 2 {
+			return
+		}
+		if *uFlag && count > 1 {
+			return
+		}
+		if *cFlag {
+			fmt.Fprintf(stdout, "%7d %s\n", count, group)
+			return
+		}
+		stdout.Write(group)
+		stdout.WriteByte('\n')
+	}
 
 	for {
 		line, err := stdin.ReadBytes('\n')
 		line = bytes.TrimRight(line, "\r\n")
-		if err == io.EOF {
-			return
-		} else if err != nil {
+		if err != nil && err != io.EOF {
 			die(err)
 		}
-
-		if !bytes.Equal(line, prevLine) {
-			_, err = stdout.Write(append(line, '\n'))
-			if err != nil {
-				die(err)
+		if len(line) > 0 || err == nil {
+			if haveGroup && equal(line, group) {
+				count++
+			} else {
+				flush()
+				group = append([]byte(nil), line...)
+				haveGroup = true
+				count = 1
 			}
-			prevLine = line
+		}
+		if err == io.EOF {
+			flush()
+			return
 		}
 	}
 }
@@ -39,4 +146,4 @@ func main() {
 func die(err error) {
 	os.Stderr.WriteString(err.Error()+"\n")
 	os.Exit(1)
-}
\ No newline at end of file
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+
+	"eaburns.cmds/internal/testutil"
+)
+
+// TestUniqFlagInteractions is a table-driven test covering combinations
+// of uniq's comparison flags (-f/-s/-w/-i) together with its output
+// flags (-c/-d/-u), since each comparison flag narrows the same
+// compareKey and a bug in one only shows up when combined with another.
+func TestUniqFlagInteractions(t *testing.T) {
+	const input = "a 1 X\na 1 x\na 2 Y\nb 1 Z\n"
+	bin := testutil.Build(t, "uniq")
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"no-flags", nil, input},
+		{"f1-s2-i-c", []string{"-f", "1", "-s", "2", "-i", "-c"},
+			"      2 a 1 X\n      1 a 2 Y\n      1 b 1 Z\n"},
+		{"f1-s2-i-d", []string{"-f", "1", "-s", "2", "-i", "-d"},
+			"a 1 X\n"},
+		{"f1-s2-i-u", []string{"-f", "1", "-s", "2", "-i", "-u"},
+			"a 2 Y\nb 1 Z\n"},
+		{"w1", []string{"-w", "1"},
+			"a 1 X\nb 1 Z\n"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			cmd := exec.Command(bin, test.args...)
+			cmd.Stdin = bytes.NewBufferString(input)
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != test.want {
+				t.Errorf("got %q, want %q", out, test.want)
+			}
+		})
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"os"
 	"text/tabwriter"
 	"unicode"
+
+	"eaburns.cmds/internal/fsopen"
 )
 
 var (
@@ -35,7 +37,13 @@ func main() {
 	var totalLines, totalWords, totalRunes, totalChars int
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 0, '\t', 0)
 	for _, path := range flag.Args() {
-		file, err := os.Open(path)
+		backend, p, err := fsopen.Parse(path)
+		if err != nil {
+			status = 1
+			os.Stderr.WriteString(err.Error()+"\n")
+			continue
+		}
+		file, err := backend.Open(p)
 		if err != nil {
 			status = 1
 			os.Stderr.WriteString(err.Error()+"\n")